@@ -0,0 +1,143 @@
+/*
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2018 Roland Singer [roland.singer@deserbit.com]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package grumble
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// App is a grumble application: a tree of commands that can either be
+// dispatched once from CLI args or run as an interactive REPL.
+type App struct {
+	// Name is the program name, used in the REPL banner/prompt and as
+	// the binary name the generated shell completion scripts shell out
+	// to.
+	Name string
+
+	commands     Commands
+	flags        Flags
+	outputFormat Format
+}
+
+// AddCommand registers cmd as a top-level command of the App.
+func (a *App) AddCommand(cmd *Command) {
+	a.commands.Add(cmd)
+}
+
+// Flags exposes the App's root-level flag registration methods (String,
+// Bool, Port, ...). Root-level flags are listed in the generated shell
+// completion scripts, but dispatch parses only the flags of the command
+// it runs; root-level flags are not parsed.
+func (a *App) Flags() *Flags {
+	return &a.flags
+}
+
+// Println prints args to the App's output, the same way in every output
+// format. Prefer Context.Encode/Context.EncodeError for command results
+// and errors, since those respect the App's structured output mode;
+// Println is for REPL chrome (banner, prompt) that only ever makes sense
+// in FormatText.
+func (a *App) Println(args ...interface{}) {
+	fmt.Println(args...)
+}
+
+// Run dispatches args against the App's command tree. If the App's
+// output format is FormatText (the default), args are optional: given
+// none, Run prints the banner and prompt and starts the interactive REPL
+// loop, reading further commands from stdin. In FormatJSON or
+// FormatJSONLines mode, Run always dispatches args exactly once and
+// returns - it never prints the banner/prompt and never starts the REPL -
+// so scripted callers get exactly one result, or one structured error, per
+// invocation.
+func (a *App) Run(args []string) error {
+	if a.RefusesInteractive() {
+		return a.dispatch(args)
+	}
+
+	if len(args) > 0 {
+		return a.dispatch(args)
+	}
+
+	a.printBanner()
+	return a.loop()
+}
+
+// loop reads whitespace-separated command lines from stdin until EOF,
+// dispatching each one without aborting the REPL. dispatch has already
+// reported any error through Context.EncodeError by the time it returns,
+// in whichever output format is active, so loop itself never prints it
+// again - it only uses the return value to decide whether to keep going,
+// which today is always, errors included.
+func (a *App) loop() error {
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print(a.Name + " > ")
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+
+		line := strings.Fields(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		a.dispatch(line)
+	}
+}
+
+// printBanner prints the App's startup banner. It is never called while
+// the App refuses interactive mode.
+func (a *App) printBanner() {
+	if len(a.Name) > 0 {
+		a.Println(a.Name)
+	}
+}
+
+// dispatch finds the command addressed by args, parses its flags out of
+// the remainder, and runs it.
+func (a *App) dispatch(args []string) error {
+	cmd, rest, err := a.commands.FindCommand(args)
+	if err != nil {
+		return (&Context{App: a}).EncodeError(err)
+	}
+	if cmd == nil {
+		return (&Context{App: a}).EncodeError(fmt.Errorf("no such command: %s", strings.Join(args, " ")))
+	}
+
+	res := FlagMap{}
+	rest, err = cmd.flags.parse(rest, res)
+	if err != nil {
+		return (&Context{App: a}).EncodeError(err)
+	}
+
+	ctx := &Context{App: a, Flags: res, RawArgs: rest}
+	if err := cmd.Run(ctx); err != nil {
+		return ctx.EncodeError(err)
+	}
+	return nil
+}
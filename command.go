@@ -0,0 +1,154 @@
+/*
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2018 Roland Singer [roland.singer@deserbit.com]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package grumble
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MisterSpacely/grumble/internal/fuzzy"
+)
+
+// Command is a single command in an App's command tree. A command may
+// have its own Run, its own subcommands, or both - a command with
+// subcommands can still do work itself when invoked with no further
+// words.
+type Command struct {
+	// Name is the word that addresses this command.
+	Name string
+
+	// Aliases are additional words that also address this command.
+	Aliases []string
+
+	// Help is a one-line description shown in generated help output.
+	Help string
+
+	// Completer, if set, overrides the default subcommand/flag
+	// completion for this command's own arguments, e.g. to suggest
+	// values looked up at runtime instead of static names.
+	Completer func(prefix string, args []string) []string
+
+	// Run is invoked with the command's parsed Context once FindCommand
+	// resolves to this command as the deepest match.
+	Run func(*Context) error
+
+	commands Commands
+	flags    Flags
+}
+
+// AddCommand registers sub as a subcommand of cmd.
+func (cmd *Command) AddCommand(sub *Command) {
+	cmd.commands.Add(sub)
+}
+
+// Flags exposes cmd's flag registration methods (String, Bool, Port, ...).
+func (cmd *Command) Flags() *Flags {
+	return &cmd.flags
+}
+
+// Commands holds a list of commands, either an App's top-level commands
+// or a Command's subcommands.
+type Commands struct {
+	list []*Command
+}
+
+// Add registers cmd.
+func (c *Commands) Add(cmd *Command) {
+	c.list = append(c.list, cmd)
+}
+
+// find returns the command named or aliased name, or nil.
+func (c *Commands) find(name string) *Command {
+	for _, cmd := range c.list {
+		if cmd.Name == name {
+			return cmd
+		}
+		for _, alias := range cmd.Aliases {
+			if alias == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// FindCommand resolves args against the command tree, descending into
+// subcommands for as long as the next word names one, and returns the
+// deepest matched command together with the args left over for it (flags
+// and positional arguments). Given no args, it returns (nil, nil, nil) -
+// there is no command to address, not an error.
+//
+// If the first word names neither a command nor anything close to one,
+// FindCommand reports an "unknown command" error; if it's a near-miss
+// (within fuzzy.MaxDistance edits of a registered name or alias), the
+// error instead suggests up to three candidates - the same "did you mean"
+// treatment Flags.parse gives a misspelled flag.
+func (c *Commands) FindCommand(args []string) (*Command, []string, error) {
+	if len(args) == 0 {
+		return nil, nil, nil
+	}
+
+	name := args[0]
+	cmd := c.find(name)
+	if cmd == nil {
+		return nil, args, c.unknownCommandError(name)
+	}
+
+	rest := args[1:]
+	if sub, subRest, err := cmd.commands.FindCommand(rest); err == nil && sub != nil {
+		return sub, subRest, nil
+	}
+	return cmd, rest, nil
+}
+
+// unknownCommandError builds an "unknown command" error for name,
+// suggesting up to three registered command names or aliases within
+// fuzzy.MaxDistance edits.
+func (c *Commands) unknownCommandError(name string) error {
+	var candidates []string
+	for _, cmd := range c.list {
+		candidates = append(candidates, cmd.Name)
+		candidates = append(candidates, cmd.Aliases...)
+	}
+
+	var matches []fuzzy.Match
+	for _, m := range fuzzy.Rank(name, candidates, fuzzy.MaxDistance) {
+		if fuzzy.Distance(name, m.Candidate, fuzzy.MaxDistance) <= fuzzy.MaxDistance {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("unknown command: %s", name)
+	}
+	if len(matches) > 3 {
+		matches = matches[:3]
+	}
+
+	suggestions := make([]string, len(matches))
+	for i, m := range matches {
+		suggestions[i] = `"` + m.Candidate + `"`
+	}
+	return fmt.Errorf("unknown command %q: did you mean %s?", name, strings.Join(suggestions, " or "))
+}
@@ -28,6 +28,8 @@ import (
 	"strings"
 
 	"github.com/desertbit/go-shlex"
+
+	"github.com/MisterSpacely/grumble/internal/fuzzy"
 )
 
 type completer struct {
@@ -98,41 +100,49 @@ func (c *completer) Do(line []rune, pos int) (newLine [][]rune, length int) {
 	}
 
 	if len(prefix) > 0 {
+		var candidates []string
 		for _, cmd := range cmds.list {
-			if strings.HasPrefix(cmd.Name, prefix) {
-				suggestions = append(suggestions, []rune(strings.TrimPrefix(cmd.Name, prefix)))
-			}
-			for _, a := range cmd.Aliases {
-				if strings.HasPrefix(a, prefix) {
-					suggestions = append(suggestions, []rune(strings.TrimPrefix(a, prefix)))
-				}
+			if cmd.Name == completeCommandName {
+				continue
 			}
+			candidates = append(candidates, cmd.Name)
+			candidates = append(candidates, cmd.Aliases...)
 		}
-
 		if flags != nil {
 			for _, f := range flags.list {
 				if len(f.Short) > 0 {
-					short := "" + f.Short //netgrumble removed -
-					if len(prefix) < len(short) && strings.HasPrefix(short, prefix) {
-						suggestions = append(suggestions, []rune(strings.TrimPrefix(short, prefix)))
-					}
+					candidates = append(candidates, f.Short) //netgrumble removed -
 				}
-				long := "" + f.Long //netgrubmle hack removed --
-				if len(prefix) < len(long) && strings.HasPrefix(long, prefix) {
-					if long != "no" {
-						suggestions = append(suggestions, []rune(strings.TrimPrefix(long, prefix)))
-					}
-
+				if f.Long != "no" {
+					candidates = append(candidates, f.Long) //netgrubmle hack removed --
 				}
 			}
 		}
+
+		// readline replaces the last len(prefix) runes of the line with
+		// whatever we return here, so only a true prefix match can be
+		// expressed as a suffix to append - a fuzzy match (subsequence,
+		// edit distance, ...) would need its own replacement length,
+		// which Do's single (newLine, length) result can't express per
+		// candidate. Fuzzy ranking is reserved for the parse "did you
+		// mean" errors, where the full candidate is printed as text
+		// instead of spliced into the input line.
+		for _, m := range fuzzy.Rank(prefix, candidates, fuzzy.MaxDistance) {
+			if m.Tier != fuzzy.ExactPrefix || len(m.Candidate) <= len(prefix) {
+				continue
+			}
+			suggestions = append(suggestions, []rune(strings.TrimPrefix(m.Candidate, prefix)))
+		}
 	} else {
 		for _, cmd := range cmds.list {
+			if cmd.Name == completeCommandName {
+				continue
+			}
 			suggestions = append(suggestions, []rune(cmd.Name))
 		}
 		if flags != nil {
 			for _, f := range flags.list {
-				if f.Long != "no" && !stringInSlice(f.Long, words) { //netgrumble we don't suggest the no command or flags that have already been set
+				if f.Long != "no" && (f.Repeatable || !stringInSlice(f.Long, words)) { //netgrumble we don't suggest the no command or flags that have already been set, unless they're repeatable
 					suggestions = append(suggestions, []rune(f.Long)) //netgrumble hack removed --
 				}
 				if len(f.Short) > 0 {
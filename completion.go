@@ -0,0 +1,304 @@
+/*
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2018 Roland Singer [roland.singer@deserbit.com]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package grumble
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// completeCommandName is the name of the hidden command that the generated
+// shell completion scripts shell out to in order to resolve dynamic
+// suggestions (flag values, positional arguments, ...) from the running
+// binary itself. It is prefixed with a double underscore so it never shows
+// up in regular help output or tab completion of the REPL.
+const completeCommandName = "__complete"
+
+// ensureCompleteCommand lazily registers the hidden __complete command the
+// generated scripts rely on. It is safe to call multiple times.
+func (a *App) ensureCompleteCommand() {
+	if cmd, _, _ := a.commands.FindCommand([]string{completeCommandName}); cmd != nil {
+		return
+	}
+
+	a.AddCommand(&Command{
+		Name: completeCommandName,
+		Help: "internal command used by shell completion scripts",
+		Run: func(c *Context) error {
+			// The generated scripts pass the command path, then "--",
+			// then the word being completed (which may be missing).
+			words := c.RawArgs
+			prefix, rest := "", words
+			if n := len(words); n > 0 && words[n-1] == "--" {
+				rest = words[:n-1]
+			} else if n > 1 && words[n-2] == "--" {
+				prefix, rest = words[n-1], words[:n-2]
+			} else if n > 0 {
+				prefix, rest = words[n-1], words[:n-1]
+			}
+			for _, s := range a.completeWords(prefix, rest) {
+				c.App.Println(s)
+			}
+			return nil
+		},
+	})
+}
+
+// completeWords returns the dynamic completion suggestions for the command
+// path in rest, given the partial word prefix. It walks the Commands tree
+// exactly like completer.Do, but returns plain words instead of runes meant
+// for readline.
+func (a *App) completeWords(prefix string, rest []string) []string {
+	cmd, remainder, err := a.commands.FindCommand(rest)
+	if err != nil {
+		return nil
+	}
+
+	cmds := &a.commands
+	var flags *Flags
+	if cmd != nil {
+		if cmd.Completer != nil {
+			return cmd.Completer(prefix, remainder)
+		}
+		cmds = &cmd.commands
+		flags = &cmd.flags
+	}
+
+	var words []string
+	for _, c := range cmds.list {
+		if c.Name == completeCommandName {
+			continue
+		}
+		if strings.HasPrefix(c.Name, prefix) {
+			words = append(words, c.Name)
+		}
+		for _, al := range c.Aliases {
+			if strings.HasPrefix(al, prefix) {
+				words = append(words, al)
+			}
+		}
+	}
+	if flags != nil {
+		for _, f := range flags.list {
+			if strings.HasPrefix(f.Long, prefix) {
+				words = append(words, f.Long)
+			}
+			if len(f.Short) > 0 && strings.HasPrefix(f.Short, prefix) {
+				words = append(words, f.Short)
+			}
+		}
+	}
+	return words
+}
+
+// commandNode is one level of the Commands tree reachable by a path of
+// command names from the root, paired with the Commands/Flags registered
+// at that level.
+type commandNode struct {
+	path     []string
+	commands *Commands
+	flags    *Flags
+}
+
+// words returns the static subcommand/alias/flag names available at n,
+// excluding the hidden completeCommandName, for splicing straight into a
+// generated completion script.
+func (n commandNode) words() []string {
+	var words []string
+	for _, cmd := range n.commands.list {
+		if cmd.Name == completeCommandName {
+			continue
+		}
+		words = append(words, cmd.Name)
+		words = append(words, cmd.Aliases...)
+	}
+	for _, f := range n.flags.list {
+		if len(f.Long) > 0 {
+			words = append(words, f.Long)
+		}
+		if len(f.Short) > 0 {
+			words = append(words, f.Short)
+		}
+	}
+	return words
+}
+
+// walkCommands collects a commandNode for the root and every subcommand
+// reachable from it, depth-first, for the Gen*Completion generators to
+// statically enumerate. cmd.Completer-driven commands are still included -
+// their static subcommand/flag names are listed, the same as any other
+// command - since the dynamic Completer only ever runs for values typed
+// after the command itself.
+func walkCommands(commands *Commands, flags *Flags, path []string, out *[]commandNode) {
+	*out = append(*out, commandNode{path: path, commands: commands, flags: flags})
+	for _, cmd := range commands.list {
+		if cmd.Name == completeCommandName {
+			continue
+		}
+		walkCommands(&cmd.commands, &cmd.flags, append(append([]string{}, path...), cmd.Name), out)
+	}
+}
+
+// GenBashCompletion generates a bash completion script for the App and
+// writes it to w. Users can make the completions available in their login
+// shell by sourcing the output, e.g.:
+//
+//	source <(myapp completion bash)
+//
+// The script statically enumerates subcommands, aliases and flags by
+// walking the Commands tree at generation time, falling back to shelling
+// out to the hidden __complete command only for dynamic suggestions (flag
+// values, Completer-driven positional arguments) a static script can't know
+// in advance.
+func (a *App) GenBashCompletion(w io.Writer) error {
+	a.ensureCompleteCommand()
+
+	name := a.Name
+	fname := bashFuncName(name)
+
+	var nodes []commandNode
+	walkCommands(&a.commands, &a.flags, nil, &nodes)
+
+	fmt.Fprintf(w, "# bash completion for %s\n\n", name)
+	fmt.Fprintf(w, "%s() {\n", fname)
+	fmt.Fprintf(w, "    local cur words cword\n")
+	fmt.Fprintf(w, "    COMPREPLY=()\n")
+	fmt.Fprintf(w, "    _get_comp_words_by_ref -n : cur words cword\n\n")
+	fmt.Fprintf(w, "    local path=\"${words[*]:1:cword-1}\"\n")
+	fmt.Fprintf(w, "    case \"$path\" in\n")
+	for _, n := range nodes {
+		words := n.words()
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "    \"%s\")\n", strings.Join(n.path, " "))
+		fmt.Fprintf(w, "        COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", strings.Join(words, " "))
+		fmt.Fprintf(w, "        return\n")
+		fmt.Fprintf(w, "        ;;\n")
+	}
+	fmt.Fprintf(w, "    esac\n\n")
+	fmt.Fprintf(w, "    local out\n")
+	fmt.Fprintf(w, "    out=$(%s %s \"${words[@]:1:cword-1}\" -- \"$cur\")\n", name, completeCommandName)
+	fmt.Fprintf(w, "    COMPREPLY=( $(compgen -W \"$out\" -- \"$cur\") )\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "complete -F %s %s\n", fname, name)
+	return nil
+}
+
+// GenZshCompletion generates a zsh completion script for the App and
+// writes it to w. Like GenBashCompletion, it statically enumerates the
+// Commands tree and only shells out to __complete for dynamic suggestions.
+func (a *App) GenZshCompletion(w io.Writer) error {
+	a.ensureCompleteCommand()
+
+	name := a.Name
+	fname := bashFuncName(name)
+
+	var nodes []commandNode
+	walkCommands(&a.commands, &a.flags, nil, &nodes)
+
+	fmt.Fprintf(w, "#compdef %s\n\n", name)
+	fmt.Fprintf(w, "%s() {\n", fname)
+	fmt.Fprintf(w, "    local path=\"${(j: :)words[2,-2]}\"\n")
+	fmt.Fprintf(w, "    local -a suggestions\n")
+	fmt.Fprintf(w, "    case \"$path\" in\n")
+	for _, n := range nodes {
+		words := n.words()
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "        \"%s\")\n", strings.Join(n.path, " "))
+		fmt.Fprintf(w, "            suggestions=(%s)\n", strings.Join(quoteAll(words), " "))
+		fmt.Fprintf(w, "            ;;\n")
+	}
+	fmt.Fprintf(w, "    esac\n\n")
+	fmt.Fprintf(w, "    if (( ${#suggestions} )); then\n")
+	fmt.Fprintf(w, "        compadd -a suggestions\n")
+	fmt.Fprintf(w, "        return\n")
+	fmt.Fprintf(w, "    fi\n\n")
+	fmt.Fprintf(w, "    suggestions=(${(f)\"$(%s %s ${words[2,-2]} -- ${words[-1]})\"})\n", name, completeCommandName)
+	fmt.Fprintf(w, "    compadd -a suggestions\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "compdef %s %s\n", fname, name)
+	return nil
+}
+
+// GenFishCompletion generates a fish completion script for the App and
+// writes it to w. Like GenBashCompletion, it statically enumerates the
+// Commands tree, registering one `complete` line per command path, and
+// only shells out to __complete as the catch-all fallback.
+func (a *App) GenFishCompletion(w io.Writer) error {
+	a.ensureCompleteCommand()
+
+	name := a.Name
+
+	var nodes []commandNode
+	walkCommands(&a.commands, &a.flags, nil, &nodes)
+
+	fmt.Fprintf(w, "# fish completion for %s\n", name)
+	for _, n := range nodes {
+		words := n.words()
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "complete -c %s -n '%s' -f -a '%s'\n", name, fishPathCondition(n.path), strings.Join(words, " "))
+	}
+	fmt.Fprintf(w, "function __%s_complete\n", name)
+	fmt.Fprintf(w, "    set -l words (commandline -opc)\n")
+	fmt.Fprintf(w, "    set -l cur (commandline -ct)\n")
+	fmt.Fprintf(w, "    %s %s $words[2..-1] -- $cur\n", name, completeCommandName)
+	fmt.Fprintf(w, "end\n")
+	fmt.Fprintf(w, "complete -c %s -f -a '(__%s_complete)'\n", name, name)
+	return nil
+}
+
+// fishPathCondition returns the `complete -n` condition under which path's
+// static words apply: for the root, while no subcommand has been typed;
+// otherwise whenever any one word of path appears on the command line
+// (__fish_seen_subcommand_from does not check the order or position of
+// path's words).
+func fishPathCondition(path []string) string {
+	if len(path) == 0 {
+		return "__fish_use_subcommand"
+	}
+	return "__fish_seen_subcommand_from " + strings.Join(path, " ")
+}
+
+// quoteAll wraps each string in single quotes for splicing into a zsh
+// array literal.
+func quoteAll(ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = "'" + s + "'"
+	}
+	return out
+}
+
+// bashFuncName turns an app name into a valid bash/zsh function identifier.
+func bashFuncName(name string) string {
+	replacer := strings.NewReplacer("-", "_", ".", "_")
+	return "_" + replacer.Replace(name) + "_complete"
+}
@@ -0,0 +1,47 @@
+/*
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2018 Roland Singer [roland.singer@deserbit.com]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package grumble
+
+// FlagMap holds the parsed value of every flag for a single command
+// invocation, keyed by long name.
+type FlagMap map[string]*FlagMapItem
+
+// FlagMapItem is the parsed value of a single flag.
+type FlagMapItem struct {
+	// Value holds the flag's value, already converted to its concrete
+	// type (string, int, IPandMASK, ...).
+	Value interface{}
+
+	// IsDefault is true when Value is the flag's compile-time default,
+	// i.e. the flag was not passed on the command line and no bound
+	// FlagSource supplied a value either.
+	IsDefault bool
+
+	// Source names where Value came from when it isn't the compile-time
+	// default: the Name() of the FlagSource that supplied it (e.g. "env"
+	// or "file:/etc/app.yml"). Empty when the value was typed on the
+	// command line or left at IsDefault.
+	Source string
+}
@@ -32,11 +32,30 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/MisterSpacely/grumble/internal/fuzzy"
 )
 
 type parseFunc func(flag, equalVal string, args []string, res FlagMap) ([]string, bool, error)
 type defaultFunc func(res FlagMap)
 
+// FlagParseError wraps a Flags.parse failure together with the flag it
+// occurred on, so output modes that need structured errors (see
+// App.SetOutputFormat) can report {"error": "...", "flag": "..."} instead
+// of losing the flag name in a formatted string.
+type FlagParseError struct {
+	Flag string
+	Err  error
+}
+
+func (e *FlagParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FlagParseError) Unwrap() error {
+	return e.Err
+}
+
 type flagItem struct {
 	Short           string
 	Long            string
@@ -44,6 +63,50 @@ type flagItem struct {
 	HelpArgs        string
 	HelpShowDefault bool
 	Default         interface{}
+
+	// Repeatable marks a flag that may be passed more than once.
+	// Repeatable flags append each occurrence to a slice; parse rejects a
+	// non-repeatable flag passed more than once with a "flag specified
+	// more than once" error.
+	Repeatable bool
+
+	// Validator, if set, runs against the converted flag value after
+	// parse has type-converted it, letting callers reject a
+	// syntactically-valid but semantically-wrong value (an out-of-range
+	// port, an IP outside an allowed CIDR, ...) without doing it by hand
+	// in the command's Run.
+	Validator func(interface{}) error
+}
+
+// SetValidator attaches validate to the flag registered under long,
+// panicking if no such flag exists. validate runs after the flag's value
+// has been parsed and type-converted, and a non-nil error aborts parse.
+func (f *Flags) SetValidator(long string, validate func(interface{}) error) {
+	for _, i := range f.list {
+		if i.Long == long {
+			i.Validator = validate
+			return
+		}
+	}
+	panic(fmt.Errorf("SetValidator: no such flag: %s", long))
+}
+
+// runValidator invokes the validator registered for long, if any, against
+// its freshly parsed value in res.
+func (f *Flags) runValidator(long string, res FlagMap) error {
+	for _, i := range f.list {
+		if i.Long != long || i.Validator == nil {
+			continue
+		}
+		item, ok := res[long]
+		if !ok {
+			return nil
+		}
+		if err := i.Validator(item.Value); err != nil {
+			return fmt.Errorf("invalid value for flag %s: %w", long, err)
+		}
+	}
+	return nil
 }
 
 // Flags holds all the registered flags.
@@ -51,6 +114,57 @@ type Flags struct {
 	parsers  []parseFunc
 	defaults map[string]defaultFunc
 	list     []*flagItem
+
+	// sources are consulted before falling back to a flag's compile-time
+	// default, ordered by kind - "CLI > env > file > default" in full,
+	// but a CLI-supplied value never reaches this list since parse fills
+	// res directly from args before the defaults loop runs at all. Within
+	// a kind, sources keep their App.BindFlagSource binding order. The
+	// first source with a value for a given flag wins.
+	sources []FlagSource
+}
+
+// addSource appends src to be consulted during parse, then stable-sorts
+// sources by kind so that, regardless of bind order, every EnvSource
+// outranks every FileSource - satisfying the framework's documented
+// precedence without requiring callers to bind sources in a particular
+// order. A FlagSource of an unrecognized kind ranks alongside EnvSource:
+// it keeps its binding-order position relative to env and other unranked
+// sources, and is consulted before every FileSource.
+func (f *Flags) addSource(src FlagSource) {
+	f.sources = append(f.sources, src)
+	sort.SliceStable(f.sources, func(i, j int) bool {
+		return sourceRank(f.sources[i]) < sourceRank(f.sources[j])
+	})
+}
+
+// fromSource fills in res[i.Long] from the first bound source that has a
+// value for the flag, reusing the flag's own parser so the value is
+// converted to the correct type exactly like a value passed on the command
+// line. It reports whether a source supplied a value. A source value that
+// fails to convert (e.g. GRUMBLE_PORT=99999, or a malformed config entry)
+// is reported as an error rather than silently falling through to the
+// next source or the compile-time default.
+func (f *Flags) fromSource(i *flagItem, res FlagMap) (bool, error) {
+	for _, src := range f.sources {
+		raw, ok := src.Lookup(i.Long)
+		if !ok {
+			continue
+		}
+		for _, p := range f.parsers {
+			_, parsed, err := p(i.Long, raw, nil, res)
+			if err != nil {
+				return false, &FlagParseError{Flag: i.Long, Err: fmt.Errorf("%s=%q from %s: %w", i.Long, raw, src.Name(), err)}
+			}
+			if parsed {
+				if item, ok := res[i.Long]; ok {
+					item.Source = src.Name()
+				}
+				return true, nil
+			}
+		}
+	}
+	return false, nil
 }
 
 // sort the flags by their name.
@@ -118,7 +232,7 @@ Loop:
 		for _, f := range f.list {
 			if len(a) <= len(f.Long) && strings.HasPrefix(f.Long, a) {
 				if full_param != "" {
-					return nil, errors.New("Ambiguous command flags: " + a + " could mean " + full_param + " or " + f.Long + ".")
+					return nil, &FlagParseError{Flag: a, Err: errors.New("Ambiguous command flags: " + a + " could mean " + full_param + " or " + f.Long + ".")}
 				}
 
 				full_param = f.Long
@@ -126,6 +240,15 @@ Loop:
 			}
 		}
 		if full_param == "" {
+			// a didn't prefix-match any registered flag. Since flags here
+			// have no leading dash to tell them apart from a positional
+			// argument, only treat a as a flag typo - and error instead of
+			// falling through to args - when it's close enough to a real
+			// flag name to plausibly be one; a token with nothing close
+			// among f.list is assumed to be a genuine positional argument.
+			if len(f.unknownFlagMatches(a)) > 0 {
+				return nil, &FlagParseError{Flag: a, Err: f.unknownFlagError(a)}
+			}
 			break Loop
 		}
 
@@ -139,15 +262,29 @@ Loop:
 			a = a[:pos]
 		}
 
+		// Reject a non-repeatable flag that has already been set.
+		for _, i := range f.list {
+			if i.Long != a {
+				continue
+			}
+			if _, ok := res[a]; ok && !i.Repeatable {
+				return nil, &FlagParseError{Flag: a, Err: fmt.Errorf("flag specified more than once: %s", a)}
+			}
+			break
+		}
+
 		for _, p := range f.parsers {
 			args, parsed, err = p(a, equalVal, args, res)
 			if err != nil {
-				return nil, err
+				return nil, &FlagParseError{Flag: a, Err: err}
 			} else if parsed {
+				if err := f.runValidator(a, res); err != nil {
+					return nil, &FlagParseError{Flag: a, Err: err}
+				}
 				continue Loop
 			}
 		}
-		return nil, fmt.Errorf("invalid flag: %s", a)
+		return nil, &FlagParseError{Flag: a, Err: f.unknownFlagError(a)}
 	}
 
 	// Finally set all the default values for not passed flags.
@@ -159,6 +296,16 @@ Loop:
 		if _, ok := res[i.Long]; ok {
 			continue
 		}
+		sourced, err := f.fromSource(i, res)
+		if err != nil {
+			return nil, err
+		}
+		if sourced {
+			if err := f.runValidator(i.Long, res); err != nil {
+				return nil, &FlagParseError{Flag: i.Long, Err: err}
+			}
+			continue
+		}
 		df, ok := f.defaults[i.Long]
 		if !ok {
 			return nil, fmt.Errorf("invalid flag: missing default function: %s", i.Long)
@@ -169,6 +316,40 @@ Loop:
 	return args, nil
 }
 
+// unknownFlagMatches fuzzy-ranks a against every registered long flag name
+// within fuzzy.MaxDistance edits.
+func (f *Flags) unknownFlagMatches(a string) []fuzzy.Match {
+	candidates := make([]string, len(f.list))
+	for i, fi := range f.list {
+		candidates[i] = fi.Long
+	}
+	var matches []fuzzy.Match
+	for _, m := range fuzzy.Rank(a, candidates, fuzzy.MaxDistance) {
+		if fuzzy.Distance(a, m.Candidate, fuzzy.MaxDistance) <= fuzzy.MaxDistance {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// unknownFlagError builds an "unknown flag" error for a, suggesting up to
+// three registered long flag names within fuzzy.MaxDistance edits.
+func (f *Flags) unknownFlagError(a string) error {
+	matches := f.unknownFlagMatches(a)
+	if len(matches) == 0 {
+		return fmt.Errorf("unknown flag: %q", a)
+	}
+	if len(matches) > 3 {
+		matches = matches[:3]
+	}
+
+	suggestions := make([]string, len(matches))
+	for i, m := range matches {
+		suggestions[i] = `"--` + m.Candidate + `"`
+	}
+	return fmt.Errorf("unknown flag %q: did you mean %s?", a, strings.Join(suggestions, " or "))
+}
+
 // StringL same as String, but without a shorthand.
 func (f *Flags) StringL(long, defaultValue, help string) {
 	f.String("", long, defaultValue, help)
@@ -477,6 +658,153 @@ func (f *Flags) Duration(short, long string, defaultValue time.Duration, help st
 		})
 }
 
+// StringSliceL same as StringSlice, but without a shorthand.
+func (f *Flags) StringSliceL(long string, help string) {
+	f.StringSlice("", long, help)
+}
+
+// StringSlice registers a repeatable string flag. Each occurrence on the
+// command line appends to the resulting []string instead of overwriting
+// the previous value, e.g. for a flag with long name "tag",
+// `tag a tag b tag c` yields []string{"a", "b", "c"}.
+func (f *Flags) StringSlice(short, long string, help string) {
+	f.register(short, long, help, "string", false, []string{},
+		func(res FlagMap) {
+			res[long] = &FlagMapItem{
+				Value:     []string{},
+				IsDefault: true,
+			}
+		},
+		func(flag, equalVal string, args []string, res FlagMap) ([]string, bool, error) {
+			if !f.match(flag, short, long) {
+				return args, false, nil
+			}
+			var vStr string
+			if len(equalVal) > 0 {
+				vStr = trimQuotes(equalVal)
+			} else if len(args) > 0 {
+				vStr = args[0]
+				args = args[1:]
+			} else {
+				return args, false, fmt.Errorf("missing string value for flag: %s", flag)
+			}
+			res[long] = &FlagMapItem{
+				Value:     append(stringSliceValue(res, long), vStr),
+				IsDefault: false,
+			}
+			return args, true, nil
+		})
+	f.list[len(f.list)-1].Repeatable = true
+}
+
+// IntSliceL same as IntSlice, but without a shorthand.
+func (f *Flags) IntSliceL(long string, help string) {
+	f.IntSlice("", long, help)
+}
+
+// IntSlice registers a repeatable int flag. Each occurrence on the command
+// line appends to the resulting []int instead of overwriting the previous
+// value.
+func (f *Flags) IntSlice(short, long string, help string) {
+	f.register(short, long, help, "int", false, []int{},
+		func(res FlagMap) {
+			res[long] = &FlagMapItem{
+				Value:     []int{},
+				IsDefault: true,
+			}
+		},
+		func(flag, equalVal string, args []string, res FlagMap) ([]string, bool, error) {
+			if !f.match(flag, short, long) {
+				return args, false, nil
+			}
+			var vStr string
+			if len(equalVal) > 0 {
+				vStr = equalVal
+			} else if len(args) > 0 {
+				vStr = args[0]
+				args = args[1:]
+			} else {
+				return args, false, fmt.Errorf("missing int value for flag: %s", flag)
+			}
+			i, err := strconv.Atoi(vStr)
+			if err != nil {
+				return args, false, fmt.Errorf("invalid int value for flag: %s", flag)
+			}
+			res[long] = &FlagMapItem{
+				Value:     append(intSliceValue(res, long), i),
+				IsDefault: false,
+			}
+			return args, true, nil
+		})
+	f.list[len(f.list)-1].Repeatable = true
+}
+
+// DurationSliceL same as DurationSlice, but without a shorthand.
+func (f *Flags) DurationSliceL(long string, help string) {
+	f.DurationSlice("", long, help)
+}
+
+// DurationSlice registers a repeatable duration flag. Each occurrence on
+// the command line appends to the resulting []time.Duration instead of
+// overwriting the previous value.
+func (f *Flags) DurationSlice(short, long string, help string) {
+	f.register(short, long, help, "duration", false, []time.Duration{},
+		func(res FlagMap) {
+			res[long] = &FlagMapItem{
+				Value:     []time.Duration{},
+				IsDefault: true,
+			}
+		},
+		func(flag, equalVal string, args []string, res FlagMap) ([]string, bool, error) {
+			if !f.match(flag, short, long) {
+				return args, false, nil
+			}
+			var vStr string
+			if len(equalVal) > 0 {
+				vStr = equalVal
+			} else if len(args) > 0 {
+				vStr = args[0]
+				args = args[1:]
+			} else {
+				return args, false, fmt.Errorf("missing duration value for flag: %s", flag)
+			}
+			d, err := time.ParseDuration(vStr)
+			if err != nil {
+				return args, false, fmt.Errorf("invalid duration value for flag: %s", flag)
+			}
+			res[long] = &FlagMapItem{
+				Value:     append(durationSliceValue(res, long), d),
+				IsDefault: false,
+			}
+			return args, true, nil
+		})
+	f.list[len(f.list)-1].Repeatable = true
+}
+
+func stringSliceValue(res FlagMap, long string) []string {
+	item, ok := res[long]
+	if !ok || item.IsDefault {
+		return nil
+	}
+	return item.Value.([]string)
+}
+
+func intSliceValue(res FlagMap, long string) []int {
+	item, ok := res[long]
+	if !ok || item.IsDefault {
+		return nil
+	}
+	return item.Value.([]int)
+}
+
+func durationSliceValue(res FlagMap, long string) []time.Duration {
+	item, ok := res[long]
+	if !ok || item.IsDefault {
+		return nil
+	}
+	return item.Value.([]time.Duration)
+}
+
 func trimQuotes(s string) string {
 	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
 		return s[1 : len(s)-1]
@@ -0,0 +1,238 @@
+/*
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2018 Roland Singer [roland.singer@deserbit.com]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package grumble
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// IPSliceL same as IPSlice, but without a shorthand.
+func (f *Flags) IPSliceL(long string, help string) {
+	f.IPSlice("", long, help)
+}
+
+// IPSlice registers a repeatable CIDR flag, e.g. for a flag with long name
+// "src", `src 10.0.0.0/8 src 192.168.0.0/16` yields []*net.IPNet with both
+// networks.
+func (f *Flags) IPSlice(short, long string, help string) {
+	f.register(short, long, help, "cidr", false, []*net.IPNet{},
+		func(res FlagMap) {
+			res[long] = &FlagMapItem{
+				Value:     []*net.IPNet{},
+				IsDefault: true,
+			}
+		},
+		func(flag, equalVal string, args []string, res FlagMap) ([]string, bool, error) {
+			if !f.match(flag, short, long) {
+				return args, false, nil
+			}
+			var vStr string
+			if len(equalVal) > 0 {
+				vStr = trimQuotes(equalVal)
+			} else if len(args) > 0 {
+				vStr = args[0]
+				args = args[1:]
+			} else {
+				return args, false, fmt.Errorf("missing cidr value for flag: %s", flag)
+			}
+			_, netw, err := net.ParseCIDR(vStr)
+			if err != nil {
+				return args, false, fmt.Errorf("invalid cidr value for flag %s: %s", flag, vStr)
+			}
+			var list []*net.IPNet
+			if item, ok := res[long]; ok && !item.IsDefault {
+				list = item.Value.([]*net.IPNet)
+			}
+			res[long] = &FlagMapItem{
+				Value:     append(list, netw),
+				IsDefault: false,
+			}
+			return args, true, nil
+		})
+	f.list[len(f.list)-1].Repeatable = true
+}
+
+// MACL same as MAC, but without a shorthand.
+func (f *Flags) MACL(long string, defaultValue net.HardwareAddr, help string) {
+	f.MAC("", long, defaultValue, help)
+}
+
+// MAC registers a hardware (MAC) address flag, parsed with net.ParseMAC.
+func (f *Flags) MAC(short, long string, defaultValue net.HardwareAddr, help string) {
+	f.register(short, long, help, "mac", true, defaultValue,
+		func(res FlagMap) {
+			res[long] = &FlagMapItem{
+				Value:     defaultValue,
+				IsDefault: true,
+			}
+		},
+		func(flag, equalVal string, args []string, res FlagMap) ([]string, bool, error) {
+			if !f.match(flag, short, long) {
+				return args, false, nil
+			}
+			var vStr string
+			if len(equalVal) > 0 {
+				vStr = trimQuotes(equalVal)
+			} else if len(args) > 0 {
+				vStr = args[0]
+				args = args[1:]
+			} else {
+				return args, false, fmt.Errorf("missing mac value for flag: %s", flag)
+			}
+			mac, err := net.ParseMAC(vStr)
+			if err != nil {
+				return args, false, fmt.Errorf("invalid mac value for flag %s: %s", flag, vStr)
+			}
+			res[long] = &FlagMapItem{
+				Value:     mac,
+				IsDefault: false,
+			}
+			return args, true, nil
+		})
+}
+
+// PortL same as Port, but without a shorthand.
+func (f *Flags) PortL(long string, defaultValue uint16, help string) {
+	f.Port("", long, defaultValue, help)
+}
+
+// Port registers a TCP/UDP port flag. Values outside the 0-65535 range are
+// rejected.
+func (f *Flags) Port(short, long string, defaultValue uint16, help string) {
+	f.register(short, long, help, "port", true, defaultValue,
+		func(res FlagMap) {
+			res[long] = &FlagMapItem{
+				Value:     defaultValue,
+				IsDefault: true,
+			}
+		},
+		func(flag, equalVal string, args []string, res FlagMap) ([]string, bool, error) {
+			if !f.match(flag, short, long) {
+				return args, false, nil
+			}
+			var vStr string
+			if len(equalVal) > 0 {
+				vStr = equalVal
+			} else if len(args) > 0 {
+				vStr = args[0]
+				args = args[1:]
+			} else {
+				return args, false, fmt.Errorf("missing port value for flag: %s", flag)
+			}
+			p, err := strconv.ParseUint(vStr, 10, 16)
+			if err != nil {
+				return args, false, fmt.Errorf("invalid port value for flag %s: must be 0-65535", flag)
+			}
+			res[long] = &FlagMapItem{
+				Value:     uint16(p),
+				IsDefault: false,
+			}
+			return args, true, nil
+		})
+}
+
+// HostPort describes a parsed "host:port" flag value.
+type HostPort struct {
+	Host string
+	Port uint16
+}
+
+func (hp HostPort) String() string {
+	return net.JoinHostPort(hp.Host, strconv.Itoa(int(hp.Port)))
+}
+
+// HostPortL same as HostPort, but without a shorthand.
+func (f *Flags) HostPortL(long string, defaultValue HostPort, help string) {
+	f.HostPort("", long, defaultValue, help)
+}
+
+// HostPort registers a "host:port" flag. Parsing only checks syntax - that
+// the value splits into a non-empty host and a 0-65535 port - since a DNS
+// lookup is blocking, network-dependent I/O that has no place running on
+// every parse (it would hang offline, in CI, or in a unit test). Attach
+// ResolvesHost via SetValidator if a command needs parse to also confirm
+// the host actually resolves.
+func (f *Flags) HostPort(short, long string, defaultValue HostPort, help string) {
+	f.register(short, long, help, "host:port", true, defaultValue,
+		func(res FlagMap) {
+			res[long] = &FlagMapItem{
+				Value:     defaultValue,
+				IsDefault: true,
+			}
+		},
+		func(flag, equalVal string, args []string, res FlagMap) ([]string, bool, error) {
+			if !f.match(flag, short, long) {
+				return args, false, nil
+			}
+			var vStr string
+			if len(equalVal) > 0 {
+				vStr = trimQuotes(equalVal)
+			} else if len(args) > 0 {
+				vStr = args[0]
+				args = args[1:]
+			} else {
+				return args, false, fmt.Errorf("missing host:port value for flag: %s", flag)
+			}
+			host, portStr, err := net.SplitHostPort(vStr)
+			if err != nil {
+				return args, false, fmt.Errorf("invalid host:port value for flag %s: %s", flag, vStr)
+			}
+			port, err := strconv.ParseUint(portStr, 10, 16)
+			if err != nil {
+				return args, false, fmt.Errorf("invalid port in flag %s: must be 0-65535", flag)
+			}
+			if len(host) == 0 {
+				return args, false, fmt.Errorf("missing host in flag %s: %s", flag, vStr)
+			}
+			res[long] = &FlagMapItem{
+				Value:     HostPort{Host: host, Port: uint16(port)},
+				IsDefault: false,
+			}
+			return args, true, nil
+		})
+}
+
+// ResolvesHost is an opt-in Validator for a HostPort flag that performs
+// the DNS lookup HostPort itself no longer does during parse. Attach it
+// with Flags.SetValidator on commands that specifically need to fail fast
+// on an unresolvable host; leave it off anywhere parse must stay fast and
+// offline-safe.
+func ResolvesHost() func(interface{}) error {
+	return func(v interface{}) error {
+		hp, ok := v.(HostPort)
+		if !ok {
+			return fmt.Errorf("ResolvesHost: value is not a HostPort: %v", v)
+		}
+		if net.ParseIP(hp.Host) != nil {
+			return nil
+		}
+		if _, err := net.LookupHost(hp.Host); err != nil {
+			return fmt.Errorf("cannot resolve host %q: %w", hp.Host, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,209 @@
+/*
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2018 Roland Singer [roland.singer@deserbit.com]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package grumble
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FlagSource supplies a flag default from somewhere other than the
+// compile-time default passed to a Flags registration method, e.g. an
+// environment variable or a config file. Sources are bound to a command
+// with App.BindFlagSource and consulted by Flags.parse before the
+// compile-time default is used: every EnvSource before every FileSource,
+// and sources of the same kind in binding order (see sourceRank).
+type FlagSource interface {
+	// Name identifies the source for the FlagMapItem.Source bit, e.g.
+	// "env" or "file:/etc/app.yml".
+	Name() string
+
+	// Lookup returns the raw string value for the flag's long name, and
+	// whether the source has a value for it at all.
+	Lookup(long string) (string, bool)
+}
+
+// sourceRank orders FlagSource kinds for precedence: lower ranks are
+// consulted first. envSource outranks fileSource so that "CLI > env >
+// file > default" holds regardless of App.BindFlagSource call order - see
+// Flags.addSource. A FlagSource of an unrecognized kind (a caller's own
+// implementation) ranks alongside envSource, so it keeps whatever
+// position binding order alone would have given it relative to other
+// unranked or env sources.
+func sourceRank(src FlagSource) int {
+	if _, ok := src.(*fileSource); ok {
+		return 1
+	}
+	return 0
+}
+
+// BindFlagSource registers src to be consulted for unset flags on the
+// command addressed by cmdPath (space separated, e.g. "server start"), or
+// on the root command if cmdPath is empty. Regardless of bind order,
+// every EnvSource takes precedence over every FileSource, and any bound
+// source takes precedence over the flag's compile-time default - see
+// Flags.addSource. Two sources of the same kind keep their relative bind
+// order.
+func (a *App) BindFlagSource(cmdPath string, src FlagSource) error {
+	flags := &a.flags
+	if len(cmdPath) > 0 {
+		cmd, rest, err := a.commands.FindCommand(strings.Fields(cmdPath))
+		if err != nil {
+			return err
+		}
+		if cmd == nil || len(rest) != 0 {
+			return fmt.Errorf("unknown command path: %s", cmdPath)
+		}
+		flags = &cmd.flags
+	}
+
+	flags.addSource(src)
+	return nil
+}
+
+// envSource is a FlagSource backed by environment variables.
+type envSource struct {
+	prefix string
+}
+
+// EnvSource returns a FlagSource that looks up a flag named "long" in the
+// environment variable PREFIX_LONG, upper-cased with dashes turned into
+// underscores, e.g. with prefix "GRUMBLE" the flag "log-level" is read
+// from GRUMBLE_LOG_LEVEL.
+func EnvSource(prefix string) FlagSource {
+	return &envSource{prefix: strings.TrimSuffix(prefix, "_")}
+}
+
+func (s *envSource) Name() string {
+	return "env"
+}
+
+func (s *envSource) Lookup(long string) (string, bool) {
+	return os.LookupEnv(s.envName(long))
+}
+
+func (s *envSource) envName(long string) string {
+	name := strings.ToUpper(strings.ReplaceAll(long, "-", "_"))
+	if len(s.prefix) == 0 {
+		return name
+	}
+	return s.prefix + "_" + name
+}
+
+// FileFormat identifies the encoding of a config file loaded with
+// FileSource.
+type FileFormat int
+
+const (
+	// FileFormatJSON parses the config file as JSON.
+	FileFormatJSON FileFormat = iota
+	// FileFormatYAML parses the config file as a flat "key: value"
+	// mapping. Nested structures are not supported; this covers the
+	// common case of a config file that only sets flag defaults.
+	FileFormatYAML
+	// FileFormatTOML parses the config file as a flat "key = value"
+	// mapping, with the same flat-mapping limitation as FileFormatYAML.
+	FileFormatTOML
+)
+
+// fileSource is a FlagSource backed by a parsed config file.
+type fileSource struct {
+	path   string
+	values map[string]string
+}
+
+// FileSource loads path in the given format and returns a FlagSource that
+// looks up flags by their long name as top-level keys. JSON values are
+// converted to their string representation; YAML and TOML are parsed as a
+// flat "key: value" / "key = value" mapping, which is sufficient for
+// supplying flag defaults even though it doesn't cover the full spec of
+// either format.
+func FileSource(path string, format FileFormat) (FlagSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	switch format {
+	case FileFormatJSON:
+		values, err = parseJSONSource(data)
+	case FileFormatYAML:
+		values = parseFlatKeyValueSource(string(data), ":")
+	case FileFormatTOML:
+		values = parseFlatKeyValueSource(string(data), "=")
+	default:
+		return nil, fmt.Errorf("unknown config file format: %v", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return &fileSource{path: path, values: values}, nil
+}
+
+func (s *fileSource) Name() string {
+	return "file:" + s.path
+}
+
+func (s *fileSource) Lookup(long string) (string, bool) {
+	v, ok := s.values[long]
+	return v, ok
+}
+
+func parseJSONSource(data []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		values[k] = fmt.Sprintf("%v", v)
+	}
+	return values, nil
+}
+
+// parseFlatKeyValueSource parses a flat "key<sep>value" mapping, one entry
+// per line. Blank lines and lines starting with '#' are ignored.
+func parseFlatKeyValueSource(data string, sep string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pos := strings.Index(line, sep)
+		if pos < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:pos])
+		val := strings.TrimSpace(line[pos+1:])
+		values[key] = trimQuotes(val)
+	}
+	return values
+}
@@ -0,0 +1,188 @@
+/*
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2018 Roland Singer [roland.singer@deserbit.com]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package fuzzy ranks a set of candidate strings against a typed input,
+// shared by the REPL completer (command/flag tab completion) and the flag
+// parser ("did you mean" errors on an unknown flag).
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Tier buckets a match by how it was found. Higher tiers sort first.
+type Tier int
+
+const (
+	// None means the candidate didn't match at all.
+	None Tier = iota
+	// EditDistance means the candidate matched only within MaxDistance
+	// Damerau-Levenshtein edits of the input.
+	EditDistance
+	// Subsequence means every rune of the input appears in the
+	// candidate, in order, ignoring case, not necessarily contiguously.
+	Subsequence
+	// CaseInsensitivePrefix means the candidate starts with the input,
+	// ignoring case.
+	CaseInsensitivePrefix
+	// ExactPrefix means the candidate starts with the input exactly.
+	ExactPrefix
+)
+
+// MaxDistance is the default Damerau-Levenshtein distance threshold beyond
+// which a candidate is not considered a match at all.
+const MaxDistance = 2
+
+// Match is a single ranked candidate.
+type Match struct {
+	Candidate string
+	Tier      Tier
+	Distance  int
+}
+
+// Rank scores every candidate against input and returns the matches in
+// best-first order, dropping anything that didn't match at all (Tier ==
+// None). maxDist bounds the edit-distance tier; pass MaxDistance unless
+// the caller needs a tighter or looser threshold.
+func Rank(input string, candidates []string, maxDist int) []Match {
+	matches := make([]Match, 0, len(candidates))
+	for _, c := range candidates {
+		if m, ok := score(input, c, maxDist); ok {
+			matches = append(matches, m)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Tier != matches[j].Tier {
+			return matches[i].Tier > matches[j].Tier
+		}
+		return matches[i].Distance < matches[j].Distance
+	})
+	return matches
+}
+
+func score(input, candidate string, maxDist int) (Match, bool) {
+	if strings.HasPrefix(candidate, input) {
+		return Match{Candidate: candidate, Tier: ExactPrefix}, true
+	}
+	if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(input)) {
+		return Match{Candidate: candidate, Tier: CaseInsensitivePrefix}, true
+	}
+	if isSubsequence(strings.ToLower(input), strings.ToLower(candidate)) {
+		return Match{Candidate: candidate, Tier: Subsequence}, true
+	}
+	if d := Distance(input, candidate, maxDist); d <= maxDist {
+		return Match{Candidate: candidate, Tier: EditDistance, Distance: d}, true
+	}
+	return Match{}, false
+}
+
+// isSubsequence reports whether every rune of input appears in candidate,
+// in the same order, not necessarily contiguously.
+func isSubsequence(input, candidate string) bool {
+	if len(input) == 0 {
+		return false
+	}
+	ri := []rune(input)
+	i := 0
+	for _, r := range candidate {
+		if i < len(ri) && r == ri[i] {
+			i++
+		}
+	}
+	return i == len(ri)
+}
+
+// Distance computes the Damerau-Levenshtein edit distance between a and b
+// (insertions, deletions, substitutions, and transpositions of two
+// adjacent runes all cost 1). It returns maxDist+1 without finishing the
+// table as soon as every cell in the current row already exceeds maxDist,
+// since the distance can only grow from there.
+func Distance(a, b string, maxDist int) int {
+	ra, rb := []rune(a), []rune(b)
+	la, lb := len(ra), len(rb)
+
+	if la == 0 {
+		return lb
+	}
+	if lb == 0 {
+		return la
+	}
+
+	// Rows indexed by a, columns by b.
+	prev2 := make([]int, lb+1)
+	prev := make([]int, lb+1)
+	cur := make([]int, lb+1)
+	for j := 0; j <= lb; j++ {
+		prev[j] = j
+	}
+
+	for i := 1; i <= la; i++ {
+		cur[0] = i
+		rowMin := cur[0]
+
+		for j := 1; j <= lb; j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			del := prev[j] + 1
+			ins := cur[j-1] + 1
+			sub := prev[j-1] + cost
+			m := min3(del, ins, sub)
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if t := prev2[j-2] + cost; t < m {
+					m = t
+				}
+			}
+
+			cur[j] = m
+			if m < rowMin {
+				rowMin = m
+			}
+		}
+
+		if rowMin > maxDist {
+			return maxDist + 1
+		}
+
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[lb]
+}
+
+func min3(a, b, c int) int {
+	m := a
+	if b < m {
+		m = b
+	}
+	if c < m {
+		m = c
+	}
+	return m
+}
@@ -0,0 +1,121 @@
+/*
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2018 Roland Singer [roland.singer@deserbit.com]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package grumble
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Format selects how a running App presents its output.
+type Format int
+
+const (
+	// FormatText is the normal human-readable REPL/CLI output. This is
+	// the default.
+	FormatText Format = iota
+	// FormatJSON marshals each result as a single JSON object.
+	FormatJSON
+	// FormatJSONLines marshals each result as its own JSON object,
+	// newline-delimited, so a long-running command can stream records
+	// for a consumer to decode one line at a time.
+	FormatJSONLines
+)
+
+// SetOutputFormat switches a to structured output. In FormatJSON or
+// FormatJSONLines mode, a.Run never prints the banner/prompt and never
+// starts the interactive REPL - see RefusesInteractive - dispatching the
+// given args exactly once instead, so the app can be piped into jq or
+// consumed by an orchestration script.
+func (a *App) SetOutputFormat(f Format) {
+	a.outputFormat = f
+}
+
+// OutputFormat returns the format most recently set with SetOutputFormat,
+// defaulting to FormatText.
+func (a *App) OutputFormat() Format {
+	return a.outputFormat
+}
+
+// RefusesInteractive reports whether a's current output format means it
+// must not enter the interactive REPL loop.
+func (a *App) RefusesInteractive() bool {
+	return a.outputFormat != FormatText
+}
+
+// jsonError is the wire shape structured output modes use to report a
+// failure, including the flag it occurred on when known.
+type jsonError struct {
+	Error string `json:"error"`
+	Flag  string `json:"flag,omitempty"`
+}
+
+// Encode writes v to c.App in its current output format: JSON (or
+// JSON-Lines, which only differs in intent - one record per line - not in
+// the per-call encoding) marshals v, while FormatText falls back to
+// printing it with the App's normal Println so commands don't need to
+// special-case text mode.
+func (c *Context) Encode(v interface{}) error {
+	if c.App.OutputFormat() == FormatText {
+		c.App.Println(v)
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.App.Println(string(data))
+	return nil
+}
+
+// EncodeError prints err to c.App in its current output format - as plain
+// text, or as a jsonError object in JSON/JSON-Lines mode, pulling the flag
+// name out of a *FlagParseError when err is one - and returns err
+// unchanged so the caller can still propagate it (e.g. as a non-zero exit
+// code). It is a no-op, returning nil, when err is nil.
+func (c *Context) EncodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if c.App.OutputFormat() == FormatText {
+		c.App.Println(err)
+		return err
+	}
+
+	jErr := jsonError{Error: err.Error()}
+	var pErr *FlagParseError
+	if errors.As(err, &pErr) {
+		jErr.Flag = pErr.Flag
+	}
+
+	data, mErr := json.Marshal(jErr)
+	if mErr != nil {
+		return mErr
+	}
+	c.App.Println(string(data))
+	return err
+}